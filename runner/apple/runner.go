@@ -6,28 +6,68 @@ import (
 	"fmt"
 	"log/slog"
 	"net/http"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/ollama/ollama/llm"
 )
 
+// CompletionBackend generates the full response text for req. It is the
+// extension point through which a real integration with Apple's on-device
+// Foundation Models framework (or any other proprietary backend) can
+// replace the built-in placeholder implementation without the HTTP layer
+// needing to change.
+type CompletionBackend func(ctx context.Context, modelName string, req llm.CompletionRequest) (string, error)
+
+func defaultCompletionBackend(_ context.Context, modelName string, req llm.CompletionRequest) (string, error) {
+	return fmt.Sprintf("response from the model '%s'. Your prompt was: %s", modelName, req.Prompt), nil
+}
+
 // Server implements the Apple Foundation Model runner
 type Server struct {
-	modelName string
-	status    llm.ServerStatus
-	progress  float32
-	ready     sync.WaitGroup
-	mu        sync.Mutex
-	log       *slog.Logger
+	modelName  string
+	status     llm.ServerStatus
+	progress   float32
+	ready      sync.WaitGroup
+	mu         sync.Mutex
+	log        *slog.Logger
+	completion CompletionBackend
+	tokenizer  Tokenizer
 }
 
-// NewServer creates a new Apple Foundation Model server
-func NewServer(modelName string) *Server {
+// ServerOption configures optional behavior on a Server.
+type ServerOption func(*Server)
+
+// WithCompletionBackend overrides the backend used to generate text for
+// Completion. See CompletionBackend.
+func WithCompletionBackend(fn CompletionBackend) ServerOption {
+	return func(s *Server) {
+		s.completion = fn
+	}
+}
+
+// WithTokenizer overrides the Tokenizer used for Tokenize/Detokenize. See
+// Tokenizer and NewVocabTokenizer.
+func WithTokenizer(t Tokenizer) ServerOption {
+	return func(s *Server) {
+		s.tokenizer = t
+	}
+}
+
+// NewServer creates a new Apple Foundation Model server. It defaults to a
+// runeTokenizer; pass NewServerFromLayers, or WithTokenizer directly, to
+// load the real tokenizer a pulled model ships with.
+func NewServer(modelName string, opts ...ServerOption) *Server {
 	s := &Server{
-		modelName: modelName,
-		status:    llm.ServerStatusLoadingModel,
-		log:       slog.With("model", modelName),
+		modelName:  modelName,
+		status:     llm.ServerStatusLoadingModel,
+		log:        slog.With("model", modelName),
+		completion: defaultCompletionBackend,
+		tokenizer:  runeTokenizer{},
+	}
+	for _, opt := range opts {
+		opt(s)
 	}
 	s.ready.Add(1)
 
@@ -45,6 +85,17 @@ func NewServer(modelName string) *Server {
 	return s
 }
 
+// NewServerFromLayers creates a new Apple Foundation Model server whose
+// tokenizer is loaded from a pulled model's manifest layers, rather than
+// falling back to the byte/rune tokenizer NewServer otherwise defaults to.
+// tokenizerLayer and modelLayer are the raw bytes of the manifest's
+// application/vnd.ollama.image.tokenizer and model layers, respectively;
+// see LoadTokenizer for how they're resolved into a Tokenizer.
+func NewServerFromLayers(modelName string, tokenizerLayer, modelLayer []byte, opts ...ServerOption) *Server {
+	opts = append([]ServerOption{WithTokenizer(LoadTokenizer(tokenizerLayer, modelLayer))}, opts...)
+	return NewServer(modelName, opts...)
+}
+
 // WaitUntilRunning waits for the server to be ready
 func (s *Server) WaitUntilRunning(ctx context.Context) error {
 	done := make(chan struct{})
@@ -102,20 +153,80 @@ func (s *Server) EstimatedVRAMByGPU(gpuID string) uint64 {
 	return 0 // Proprietary models don't use GPU VRAM
 }
 
-// Completion handles text generation requests
+// Completion handles text generation requests, streaming the response back
+// through fn one token at a time and honoring ctx cancellation between
+// tokens.
 func (s *Server) Completion(ctx context.Context, req llm.CompletionRequest, fn func(llm.CompletionResponse)) error {
 	s.ready.Wait()
-	response := fmt.Sprintf("response from the model '%s'. Your prompt was: %s", s.modelName, req.Prompt)
-	// Call the callback function with the response
+
+	promptTokens, err := s.tokenizer.Encode(req.Prompt)
+	if err != nil {
+		return fmt.Errorf("tokenize prompt: %w", err)
+	}
+
+	promptStart := time.Now()
+	response, err := s.completion(ctx, s.modelName, req)
+	if err != nil {
+		return err
+	}
+	promptEvalDuration := time.Since(promptStart)
+
+	tokens := tokenize(response)
+
+	evalStart := time.Now()
+	var evalCount int
+	for _, token := range tokens {
+		select {
+		case <-ctx.Done():
+			fn(llm.CompletionResponse{
+				Done:               true,
+				DoneReason:         llm.DoneReasonStop, // github.com/ollama/ollama/llm has no DoneReasonAbort as of this writing; closest available
+				PromptEvalCount:    len(promptTokens),
+				PromptEvalDuration: promptEvalDuration,
+				EvalCount:          evalCount,
+				EvalDuration:       time.Since(evalStart),
+			})
+			return ctx.Err()
+		default:
+		}
+
+		evalCount++
+		fn(llm.CompletionResponse{Content: token})
+	}
+
 	fn(llm.CompletionResponse{
-		Content:    response,
-		DoneReason: llm.DoneReasonStop,
-		Done:       true,
+		Done:               true,
+		DoneReason:         llm.DoneReasonStop,
+		PromptEvalCount:    len(promptTokens),
+		PromptEvalDuration: promptEvalDuration,
+		EvalCount:          evalCount,
+		EvalDuration:       time.Since(evalStart),
 	})
 
 	return nil
 }
 
+// tokenize splits s into chunks suitable for incremental streaming, ending
+// a chunk after each space character (other whitespace does not split), so
+// concatenating them reproduces s exactly.
+func tokenize(s string) []string {
+	var tokens []string
+
+	var b strings.Builder
+	for _, r := range s {
+		b.WriteRune(r)
+		if r == ' ' {
+			tokens = append(tokens, b.String())
+			b.Reset()
+		}
+	}
+	if b.Len() > 0 {
+		tokens = append(tokens, b.String())
+	}
+
+	return tokens
+}
+
 // Embedding handles embedding requests
 func (s *Server) Embedding(ctx context.Context, input string) ([]float32, error) {
 	s.ready.Wait()
@@ -132,28 +243,13 @@ func (s *Server) Embedding(ctx context.Context, input string) ([]float32, error)
 // Tokenize handles tokenization requests
 func (s *Server) Tokenize(ctx context.Context, content string) ([]int, error) {
 	s.ready.Wait()
-
-	// For now, return a simple character-based tokenization
-	tokens := make([]int, len(content))
-	for i, char := range content {
-		tokens[i] = int(char)
-	}
-
-	return tokens, nil
+	return s.tokenizer.Encode(content)
 }
 
 // Detokenize handles detokenization requests
 func (s *Server) Detokenize(ctx context.Context, tokens []int) (string, error) {
 	s.ready.Wait()
-
-	// TODO: Replace with your actual detokenization logic
-	// For now, return a simple character-based detokenization
-	content := make([]rune, len(tokens))
-	for i, token := range tokens {
-		content[i] = rune(token)
-	}
-
-	return string(content), nil
+	return s.tokenizer.Decode(tokens)
 }
 
 // Health returns server health status
@@ -0,0 +1,160 @@
+package proprietary
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Tokenizer converts between text and the token IDs a proprietary backend
+// expects. It is the extension point through which a real tokenizer
+// (tokenizer.json, SentencePiece, or anything else a backend supplies) can
+// replace the built-in rune-per-token fallback, runeTokenizer.
+type Tokenizer interface {
+	Encode(text string) ([]int, error)
+	Decode(tokens []int) (string, error)
+}
+
+// vocabTokenizer is a greedy longest-match tokenizer loaded from a
+// tokenizer.json-style vocab. Any byte sequence not covered by the vocab
+// falls back to a per-byte token, so Decode(Encode(s)) always reproduces s
+// exactly regardless of vocab coverage.
+type vocabTokenizer struct {
+	tokenToID   map[string]int
+	idToToken   map[int]string
+	maxTokenLen int
+	byteBase    int
+}
+
+// NewVocabTokenizer loads a tokenizer from tokenizer.json-formatted data,
+// i.e. {"model":{"vocab":{"token":id, ...}}}.
+func NewVocabTokenizer(data []byte) (Tokenizer, error) {
+	var doc struct {
+		Model struct {
+			Vocab map[string]int `json:"vocab"`
+		} `json:"model"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("tokenizer: %w", err)
+	}
+	if len(doc.Model.Vocab) == 0 {
+		return nil, fmt.Errorf("tokenizer: vocab is empty")
+	}
+
+	t := &vocabTokenizer{
+		tokenToID: doc.Model.Vocab,
+		idToToken: make(map[int]string, len(doc.Model.Vocab)),
+	}
+
+	maxID := -1
+	for tok, id := range t.tokenToID {
+		t.idToToken[id] = tok
+		if len(tok) > t.maxTokenLen {
+			t.maxTokenLen = len(tok)
+		}
+		if id > maxID {
+			maxID = id
+		}
+	}
+	t.byteBase = maxID + 1
+
+	return t, nil
+}
+
+func (t *vocabTokenizer) Encode(text string) ([]int, error) {
+	data := []byte(text)
+
+	var ids []int
+	for len(data) > 0 {
+		id, n := t.longestMatch(data)
+		if n == 0 {
+			ids = append(ids, t.byteBase+int(data[0]))
+			data = data[1:]
+			continue
+		}
+
+		ids = append(ids, id)
+		data = data[n:]
+	}
+
+	return ids, nil
+}
+
+func (t *vocabTokenizer) longestMatch(data []byte) (id, n int) {
+	limit := t.maxTokenLen
+	if limit > len(data) {
+		limit = len(data)
+	}
+
+	for length := limit; length > 0; length-- {
+		if id, ok := t.tokenToID[string(data[:length])]; ok {
+			return id, length
+		}
+	}
+
+	return 0, 0
+}
+
+func (t *vocabTokenizer) Decode(tokens []int) (string, error) {
+	var b strings.Builder
+	for _, id := range tokens {
+		if id >= t.byteBase {
+			if b2 := id - t.byteBase; b2 < 256 {
+				b.WriteByte(byte(b2))
+				continue
+			}
+		}
+
+		tok, ok := t.idToToken[id]
+		if !ok {
+			return "", fmt.Errorf("tokenizer: unknown token id %d", id)
+		}
+		b.WriteString(tok)
+	}
+
+	return b.String(), nil
+}
+
+// LoadTokenizer builds a Tokenizer from layer contents gathered at model
+// load time. It prefers a dedicated tokenizer layer
+// (application/vnd.ollama.image.tokenizer); if none is present, it falls
+// back to parsing the model layer itself, since some backends embed
+// tokenizer metadata alongside the weights. If neither yields a usable
+// vocab, it falls back to runeTokenizer.
+func LoadTokenizer(tokenizerLayer, modelLayer []byte) Tokenizer {
+	if len(tokenizerLayer) > 0 {
+		if t, err := NewVocabTokenizer(tokenizerLayer); err == nil {
+			return t
+		}
+	}
+
+	if len(modelLayer) > 0 {
+		if t, err := NewVocabTokenizer(modelLayer); err == nil {
+			return t
+		}
+	}
+
+	return runeTokenizer{}
+}
+
+// runeTokenizer is the fallback used when no dedicated tokenizer is
+// configured. It maps each rune directly to its code point, which is
+// simple but collides with real vocab IDs — Server prefers a
+// vocabTokenizer whenever a tokenizer layer is available.
+type runeTokenizer struct{}
+
+func (runeTokenizer) Encode(text string) ([]int, error) {
+	tokens := make([]int, 0, len(text))
+	for _, r := range text {
+		tokens = append(tokens, int(r))
+	}
+	return tokens, nil
+}
+
+func (runeTokenizer) Decode(tokens []int) (string, error) {
+	runes := make([]rune, len(tokens))
+	for i, t := range tokens {
+		runes[i] = rune(t)
+	}
+	return string(runes), nil
+}
@@ -0,0 +1,66 @@
+package proprietary
+
+import "testing"
+
+func TestVocabTokenizerRoundTrip(t *testing.T) {
+	tokenizer, err := NewVocabTokenizer(appleFoundationModelTestVocab)
+	if err != nil {
+		t.Fatalf("NewVocabTokenizer: %v", err)
+	}
+
+	cases := []string{
+		"",
+		"hello",
+		"<|im_start|>system\nhello<|im_end|>",
+		"unseen text with punctuation! 日本語 emoji 🦙",
+		"repeated repeated repeated tokens",
+	}
+
+	for _, s := range cases {
+		tokens, err := tokenizer.Encode(s)
+		if err != nil {
+			t.Fatalf("Encode(%q): %v", s, err)
+		}
+
+		got, err := tokenizer.Decode(tokens)
+		if err != nil {
+			t.Fatalf("Decode(%q): %v", s, err)
+		}
+
+		if got != s {
+			t.Errorf("round trip mismatch: Encode/Decode(%q) = %q", s, got)
+		}
+	}
+}
+
+func TestRuneTokenizerRoundTrip(t *testing.T) {
+	var tokenizer runeTokenizer
+
+	s := "hello, 世界"
+	tokens, err := tokenizer.Encode(s)
+	if err != nil {
+		t.Fatalf("Encode(%q): %v", s, err)
+	}
+
+	got, err := tokenizer.Decode(tokens)
+	if err != nil {
+		t.Fatalf("Decode(%q): %v", s, err)
+	}
+
+	if got != s {
+		t.Errorf("round trip mismatch: Encode/Decode(%q) = %q", s, got)
+	}
+}
+
+var appleFoundationModelTestVocab = []byte(`{
+	"model": {
+		"vocab": {
+			"<|im_start|>": 1,
+			"<|im_end|>": 2,
+			"system": 3,
+			"hello": 4,
+			"repeated": 5,
+			" ": 6
+		}
+	}
+}`)
@@ -0,0 +1,257 @@
+package server
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// refCountLocks serializes adjustRefCount's read-modify-write per digest, so
+// two concurrent writers touching a shared layer (e.g. two pulls sharing a
+// base layer) can't both read the same stale count and lose an increment or
+// decrement. It only guards this process; a .refs file is still not safe to
+// update from two processes at once.
+var refCountLocks sync.Map // digest string -> *sync.Mutex
+
+func lockRefCount(digest string) *sync.Mutex {
+	v, _ := refCountLocks.LoadOrStore(digest, new(sync.Mutex))
+	return v.(*sync.Mutex)
+}
+
+// splitDigest splits a digest of the form "<algorithm>:<hex>" into its two
+// parts, e.g. "sha256:abcd..." -> ("sha256", "abcd...").
+func splitDigest(digest string) (algo, hash string, err error) {
+	algo, hash, ok := strings.Cut(digest, ":")
+	if !ok || algo == "" || hash == "" {
+		return "", "", fmt.Errorf("invalid digest %q", digest)
+	}
+
+	return algo, hash, nil
+}
+
+// verifyLayerDigest confirms that the blob for layer actually hashes to
+// layer.Digest, refusing to let a manifest reference a blob it doesn't
+// match.
+func verifyLayerDigest(layer Layer) error {
+	if layer.Digest == "" {
+		return nil
+	}
+
+	blob, err := GetBlobsPath(layer.Digest)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Open(blob)
+	if err != nil {
+		return fmt.Errorf("%s: %w", layer.Digest, err)
+	}
+	defer f.Close()
+
+	sum := sha256.New()
+	if _, err := io.Copy(sum, f); err != nil {
+		return err
+	}
+
+	_, want, err := splitDigest(layer.Digest)
+	if err != nil {
+		return err
+	}
+
+	if got := hex.EncodeToString(sum.Sum(nil)); got != want {
+		return fmt.Errorf("%s: digest mismatch, blob hashes to sha256:%s", layer.Digest, got)
+	}
+
+	return nil
+}
+
+// refCountPath returns the path of the reference-count file for digest,
+// rooted under the blob store alongside the blobs themselves.
+func refCountPath(digest string) (string, error) {
+	algo, hash, err := splitDigest(digest)
+	if err != nil {
+		return "", err
+	}
+
+	root, err := GetBlobsPath("")
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(root, algo, hash+".refs"), nil
+}
+
+// refCount returns the current reference count for digest. A digest with
+// no .refs file has a count of zero.
+func refCount(digest string) (int, error) {
+	path, err := refCountPath(digest)
+	if err != nil {
+		return 0, err
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	} else if err != nil {
+		return 0, err
+	}
+
+	count, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return count, nil
+}
+
+// adjustRefCount atomically adds delta to digest's reference count and
+// returns the resulting count. The count floors at zero.
+func adjustRefCount(digest string, delta int) (int, error) {
+	mu := lockRefCount(digest)
+	mu.Lock()
+	defer mu.Unlock()
+
+	path, err := refCountPath(digest)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return 0, err
+	}
+
+	count, err := refCount(digest)
+	if err != nil {
+		return 0, err
+	}
+
+	count += delta
+	if count < 0 {
+		count = 0
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(strconv.Itoa(count)), 0o644); err != nil {
+		return 0, err
+	}
+
+	if err := os.Rename(tmp, path); err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// blobDigestFromFilename reverses the "sha256-<hash>" naming GetBlobsPath
+// uses on disk back into a "sha256:<hash>" digest. It returns "" for
+// anything that isn't a blob file (e.g. the refs subdirectory).
+func blobDigestFromFilename(name string) string {
+	algo, hash, ok := strings.Cut(name, "-")
+	if !ok || algo != "sha256" || len(hash) != 2*sha256.Size {
+		return ""
+	}
+	if _, err := hex.DecodeString(hash); err != nil {
+		return ""
+	}
+
+	return algo + ":" + hash
+}
+
+// liveDigests returns the set of blob digests reachable from every current
+// manifest, including synthetic manifests produced by registered
+// ManifestProviders. Not every manifest writer maintains the incremental
+// ref-count index (a ManifestProvider, for instance, never calls
+// WriteManifest), so this is the source of truth GC and RemoveLayers fall
+// back to rather than trusting a ref count of zero on its own.
+func liveDigests() (map[string]bool, error) {
+	ms, err := Manifests(true)
+	if err != nil {
+		return nil, err
+	}
+
+	live := make(map[string]bool)
+	for _, m := range ms {
+		for _, layer := range append(m.Layers, m.Config) {
+			if layer.Digest != "" {
+				live[layer.Digest] = true
+			}
+		}
+
+		// A manifest resolved out of an image index is itself a blob.
+		if m.digest != "" {
+			live["sha256:"+m.digest] = true
+		}
+	}
+
+	return live, nil
+}
+
+// GC removes blobs that are no longer referenced by any manifest,
+// including synthetic manifests produced by registered ManifestProviders.
+// It mirrors the mark-and-sweep model used by container registries: the
+// live set is the digests reachable from every manifest right now, and
+// anything else on disk is garbage. If dryRun is true, GC reports how many
+// bytes it would free without deleting anything.
+func GC(ctx context.Context, dryRun bool) (freed int64, err error) {
+	live, err := liveDigests()
+	if err != nil {
+		return 0, err
+	}
+
+	root, err := GetBlobsPath("")
+	if err != nil {
+		return 0, err
+	}
+
+	entries, err := os.ReadDir(root)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	} else if err != nil {
+		return 0, err
+	}
+
+	for _, entry := range entries {
+		if err := ctx.Err(); err != nil {
+			return freed, err
+		}
+
+		if entry.IsDir() {
+			continue
+		}
+
+		digest := blobDigestFromFilename(entry.Name())
+		if digest == "" || live[digest] {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return freed, err
+		}
+
+		if !dryRun {
+			if err := os.Remove(filepath.Join(root, entry.Name())); err != nil {
+				return freed, err
+			}
+
+			if refs, err := refCountPath(digest); err == nil {
+				if err := os.Remove(refs); err != nil && !errors.Is(err, os.ErrNotExist) {
+					slog.Warn("failed to remove ref count file", "digest", digest, "error", err)
+				}
+			}
+		}
+
+		freed += info.Size()
+	}
+
+	return freed, nil
+}
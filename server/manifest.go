@@ -10,8 +10,7 @@ import (
 	"log/slog"
 	"os"
 	"path/filepath"
-	"reflect"
-	"time"
+	"runtime"
 
 	"github.com/ollama/ollama/types/model"
 )
@@ -41,6 +40,19 @@ func (m *Manifest) Remove() error {
 		return err
 	}
 
+	for _, layer := range append(m.Layers, m.Config) {
+		if layer.Digest == "" {
+			continue
+		}
+		if _, err := adjustRefCount(layer.Digest, -1); err != nil {
+			slog.Warn("failed to decrement ref count", "digest", layer.Digest, "error", err)
+		}
+	}
+
+	if err := os.Remove(signaturePath(m.filepath)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		slog.Warn("failed to remove manifest signature", "path", m.filepath, "error", err)
+	}
+
 	manifests, err := GetManifestPath()
 	if err != nil {
 		return err
@@ -49,15 +61,43 @@ func (m *Manifest) Remove() error {
 	return PruneDirectory(manifests)
 }
 
+// RemoveLayers deletes any of the manifest's layers whose reference count
+// has dropped to zero, leaving blobs still shared with other manifests in
+// place. Call Remove first so this manifest's own references have already
+// been released. Not every manifest writer (e.g. a ManifestProvider) bumps
+// the ref-count index, so a zero count is corroborated against a live scan
+// of every current manifest before a blob is actually deleted.
 func (m *Manifest) RemoveLayers() error {
+	var live map[string]bool
+
 	for _, layer := range append(m.Layers, m.Config) {
-		if layer.Digest != "" {
-			if err := layer.Remove(); errors.Is(err, os.ErrNotExist) {
-				slog.Debug("layer does not exist", "digest", layer.Digest)
-			} else if err != nil {
+		if layer.Digest == "" {
+			continue
+		}
+
+		if count, err := refCount(layer.Digest); err != nil {
+			return err
+		} else if count > 0 {
+			continue
+		}
+
+		if live == nil {
+			var err error
+			live, err = liveDigests()
+			if err != nil {
 				return err
 			}
 		}
+
+		if live[layer.Digest] {
+			continue
+		}
+
+		if err := layer.Remove(); errors.Is(err, os.ErrNotExist) {
+			slog.Debug("layer does not exist", "digest", layer.Digest)
+		} else if err != nil {
+			return err
+		}
 	}
 
 	return nil
@@ -73,9 +113,37 @@ func ParseNamedManifest(n model.Name) (*Manifest, error) {
 		return nil, err
 	}
 
-	p := filepath.Join(manifests, n.Filepath())
+	return parseManifestFile(filepath.Join(manifests, n.Filepath()), n, true)
+}
 
-	var m Manifest
+// readNamedManifest loads n's manifest without verifying its signature. It
+// exists for internal bookkeeping — listing, GC, and "what manifest is
+// this write replacing" — that must see every manifest already on disk
+// regardless of OLLAMA_VERIFY_SIGNATURES. Signature verification gates
+// serving a model to a caller; it isn't a license to pretend a manifest
+// (and the blobs it references) don't exist.
+func readNamedManifest(n model.Name) (*Manifest, error) {
+	if !n.IsFullyQualified() {
+		return nil, model.Unqualified(n)
+	}
+
+	manifests, err := GetManifestPath()
+	if err != nil {
+		return nil, err
+	}
+
+	return parseManifestFile(filepath.Join(manifests, n.Filepath()), n, false)
+}
+
+// parseManifestFile decodes the manifest stored at p, belonging to name. If
+// p is an OCI image index or Docker manifest list, it resolves to the
+// sub-manifest matching the running platform and parses that instead. If
+// verify is true and OLLAMA_VERIFY_SIGNATURES is set, it also verifies p's
+// detached signature against the keys name's registry is trusted for; a
+// sub-manifest resolved out of an already-verified index is addressed by
+// digest and doesn't carry (or need) its own signature, since the index's
+// signature already commits to it.
+func parseManifestFile(p string, name model.Name, verify bool) (*Manifest, error) {
 	f, err := os.Open(p)
 	if err != nil {
 		return nil, err
@@ -87,43 +155,144 @@ func ParseNamedManifest(n model.Name) (*Manifest, error) {
 		return nil, err
 	}
 
-	sha256sum := sha256.New()
-	if err := json.NewDecoder(io.TeeReader(f, sha256sum)).Decode(&m); err != nil {
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return nil, err
+	}
+
+	if verify && verificationRequired() {
+		if err := verifyManifestSignature(p, data, registryOf(name), trustPolicy()); err != nil {
+			return nil, err
+		}
+	}
+
+	var envelope struct {
+		MediaType string `json:"mediaType"`
+	}
+	if err := json.Unmarshal(data, &envelope); err != nil {
 		return nil, err
 	}
 
+	if isManifestList(envelope.MediaType) {
+		var list ManifestList
+		if err := json.Unmarshal(data, &list); err != nil {
+			return nil, err
+		}
+
+		desc, err := list.Select(runtime.GOOS, runtime.GOARCH, "")
+		if err != nil {
+			return nil, err
+		}
+
+		blob, err := GetBlobsPath(desc.Digest)
+		if err != nil {
+			return nil, err
+		}
+
+		sub, err := parseManifestFile(blob, name, false)
+		if err != nil {
+			return nil, err
+		}
+
+		if got := "sha256:" + sub.digest; got != desc.Digest {
+			return nil, fmt.Errorf("%s: digest mismatch, blob hashes to %s", desc.Digest, got)
+		}
+
+		return sub, nil
+	}
+
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+
+	sum := sha256.Sum256(data)
+
 	m.filepath = p
 	m.fi = fi
-	m.digest = hex.EncodeToString(sha256sum.Sum(nil))
+	m.digest = hex.EncodeToString(sum[:])
 
 	return &m, nil
 }
 
+// WriteManifest writes a manifest using the legacy Docker distribution
+// schema 2 media type. Use WriteOCIManifest for models addressed by an
+// OCI-conformant registry.
 func WriteManifest(name model.Name, config Layer, layers []Layer) error {
+	return writeManifest(name, MediaTypeDockerManifest, config, layers)
+}
+
+// WriteOCIManifest writes a manifest using the OCI image-spec v1 media
+// type instead of the legacy Docker distribution schema.
+func WriteOCIManifest(name model.Name, config Layer, layers []Layer) error {
+	return writeManifest(name, MediaTypeOCIManifest, config, layers)
+}
+
+func writeManifest(name model.Name, mediaType string, config Layer, layers []Layer) error {
+	for _, layer := range append(layers, config) {
+		if err := verifyLayerDigest(layer); err != nil {
+			return fmt.Errorf("refusing to write manifest: %w", err)
+		}
+	}
+
 	manifests, err := GetManifestPath()
 	if err != nil {
 		return err
 	}
 
-	p := filepath.Join(manifests, name.Filepath())
-	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+	var replaced []Layer
+	if previous, err := readNamedManifest(name); err == nil {
+		replaced = append(previous.Layers, previous.Config)
+	} else if !errors.Is(err, os.ErrNotExist) {
 		return err
 	}
 
-	f, err := os.Create(p)
-	if err != nil {
+	p := filepath.Join(manifests, name.Filepath())
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
 		return err
 	}
-	defer f.Close()
 
 	m := Manifest{
 		SchemaVersion: 2,
-		MediaType:     "application/vnd.docker.distribution.manifest.v2+json",
+		MediaType:     mediaType,
 		Config:        config,
 		Layers:        layers,
 	}
 
-	return json.NewEncoder(f).Encode(m)
+	data, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(p, data, 0o644); err != nil {
+		return err
+	}
+
+	if key, ok := signingKey(); ok {
+		if err := signManifest(p, data, key); err != nil {
+			slog.Warn("failed to sign manifest", "name", name, "error", err)
+		}
+	}
+
+	for _, layer := range replaced {
+		if layer.Digest == "" {
+			continue
+		}
+		if _, err := adjustRefCount(layer.Digest, -1); err != nil {
+			slog.Warn("failed to decrement ref count", "digest", layer.Digest, "error", err)
+		}
+	}
+
+	for _, layer := range append(layers, config) {
+		if layer.Digest == "" {
+			continue
+		}
+		if _, err := adjustRefCount(layer.Digest, 1); err != nil {
+			slog.Warn("failed to increment ref count", "digest", layer.Digest, "error", err)
+		}
+	}
+
+	return nil
 }
 
 func Manifests(continueOnError bool) (map[model.Name]*Manifest, error) {
@@ -164,7 +333,7 @@ func Manifests(continueOnError bool) (map[model.Name]*Manifest, error) {
 				continue
 			}
 
-			m, err := ParseNamedManifest(n)
+			m, err := readNamedManifest(n)
 			if err != nil {
 				if !continueOnError {
 					return nil, fmt.Errorf("%s %w", n, err)
@@ -177,131 +346,31 @@ func Manifests(continueOnError bool) (map[model.Name]*Manifest, error) {
 		}
 	}
 
-	// Add models
-	appleFoundationModelEnabled := os.Getenv("OLLAMA_ENABLE_APPLE_FOUNDATION_MODEL") == "1"
-	slog.Info("check for Apple Foundation Models", slog.Bool("enabled", appleFoundationModelEnabled))
-	if appleFoundationModelEnabled {
-		manifests, err := createAppleManifests()
+	// Merge in models from registered providers (Apple Foundation Models,
+	// and future providers such as MLX or CoreML).
+	for _, provider := range manifestProviders {
+		names, err := provider.List()
 		if err != nil {
 			if !continueOnError {
-				return nil, fmt.Errorf("failed to get models: %w", err)
-			}
-			slog.Warn("failed to get models", "error", err)
-		} else {
-			for name, manifest := range manifests {
-				ms[name] = manifest
+				return nil, fmt.Errorf("failed to list models: %w", err)
 			}
+			slog.Warn("failed to list models", "error", err)
+			continue
 		}
-	}
 
-	return ms, nil
-}
-
-// createAppleManifests creates manifests for Apple Foundation Models:
-func createAppleManifests() (map[model.Name]*Manifest, error) {
-	manifests := make(map[model.Name]*Manifest)
-	name := model.ParseName("apple")
-	if !name.IsValid() {
-		return nil, fmt.Errorf("invalid model name")
-	}
-
-	// Create a minimal config layer
-	config := ConfigV2{
-		OS:           "linux",
-		Architecture: "amd64",
-		RootFS: RootFS{
-			Type: "layers",
-		},
-		ModelFormat:   "apple",
-		ModelFamily:   "apple",
-		ModelType:     "apple",
-		FileType:      "apple",
-		ModelFamilies: []string{"apple"},
-	}
-
-	fakeDigest := GenerateFakeDigest("apple")
-
-	configData, err := json.Marshal(config)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal config: %w", err)
-	}
-
-	// Create config layer
-	configLayer := Layer{
-		Digest:    fakeDigest,
-		MediaType: "application/vnd.ollama.image.config",
-		Size:      int64(len(configData)),
-	}
-
-	// Create a placeholder model layer
-	modelLayer := Layer{
-		Digest:    fakeDigest,
-		MediaType: "application/vnd.ollama.image.applefoundationmodel",
-		Size:      1024 * 1024, // 1MB placeholder
-	}
-
-	// Create template layer
-	templateData := []byte(`{{ if .System }}<|im_start|>system
-{{ .System }}<|im_end|>
-{{ end }}{{ if .Prompt }}<|im_start|>user
-{{ .Prompt }}<|im_end|>
-<|im_start|>assistant
-{{ end }}{{ .Response }}<|im_end|>`)
-
-	templateLayer := Layer{
-		Digest:    fakeDigest,
-		MediaType: "application/vnd.ollama.image.template",
-		Size:      int64(len(templateData)),
-	}
-
-	manifest := &Manifest{
-		SchemaVersion:          2,
-		MediaType:              "application/vnd.docker.distribution.manifest.v2+json",
-		Config:                 configLayer,
-		Layers:                 []Layer{modelLayer, templateLayer},
-		IsAppleFoundationModel: true,
-	}
-
-	// Set file info using reflection
-	manifestValue := reflect.ValueOf(manifest).Elem()
-	filepathField := manifestValue.FieldByName("filepath")
-	fiField := manifestValue.FieldByName("fi")
-	// digestField := manifestValue.FieldByName("digest")
+		for _, name := range names {
+			m, err := provider.Get(name)
+			if err != nil {
+				if !continueOnError {
+					return nil, fmt.Errorf("%s: %w", name, err)
+				}
+				slog.Warn("failed to get model", "name", name, "error", err)
+				continue
+			}
 
-	if filepathField.IsValid() && filepathField.CanSet() {
-		filepathField.SetString("")
-	}
-	if fiField.IsValid() && fiField.CanSet() {
-		fiField.Set(reflect.ValueOf(&fakeFileInfo{
-			name:    name.Filepath(),
-			size:    manifest.Size(),
-			mode:    0644,
-			modTime: time.Now(),
-		}))
+			ms[name] = m
+		}
 	}
 
-	manifests[name] = manifest
-	slog.Info("registered Apple Foundation Model")
-
-	return manifests, nil
-}
-
-func GenerateFakeDigest(name string) string {
-	hash := sha256.Sum256([]byte("apple:" + name))
-	return hex.EncodeToString(hash[:])
-}
-
-// fakeFileInfo implements os.FileInfo for the Apple Foundation Model
-type fakeFileInfo struct {
-	name    string
-	size    int64
-	mode    os.FileMode
-	modTime time.Time
+	return ms, nil
 }
-
-func (f *fakeFileInfo) Name() string       { return f.name }
-func (f *fakeFileInfo) Size() int64        { return f.size }
-func (f *fakeFileInfo) Mode() os.FileMode  { return f.mode }
-func (f *fakeFileInfo) ModTime() time.Time { return f.modTime }
-func (f *fakeFileInfo) IsDir() bool        { return false }
-func (f *fakeFileInfo) Sys() interface{}   { return nil }
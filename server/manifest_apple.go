@@ -0,0 +1,190 @@
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/ollama/ollama/types/model"
+)
+
+func init() {
+	RegisterManifestProvider(&appleFoundationModelProvider{})
+}
+
+const appleFoundationModelName = "apple"
+
+// appleFoundationModelProvider exposes Apple's on-device Foundation Models
+// framework as a synthetic, read-only model in the local store. It is
+// gated behind OLLAMA_ENABLE_APPLE_FOUNDATION_MODEL since the framework is
+// only available on supported Apple hardware.
+type appleFoundationModelProvider struct{}
+
+func (p *appleFoundationModelProvider) enabled() bool {
+	return os.Getenv("OLLAMA_ENABLE_APPLE_FOUNDATION_MODEL") == "1"
+}
+
+func (p *appleFoundationModelProvider) MediaType() string {
+	return "application/vnd.ollama.image.applefoundationmodel"
+}
+
+func (p *appleFoundationModelProvider) List() ([]model.Name, error) {
+	if !p.enabled() {
+		return nil, nil
+	}
+
+	name := model.ParseName(appleFoundationModelName)
+	if !name.IsValid() {
+		return nil, fmt.Errorf("invalid model name %q", appleFoundationModelName)
+	}
+
+	return []model.Name{name}, nil
+}
+
+func (p *appleFoundationModelProvider) Get(name model.Name) (*Manifest, error) {
+	if !p.enabled() {
+		return nil, fmt.Errorf("apple foundation model provider is disabled")
+	}
+
+	want := model.ParseName(appleFoundationModelName)
+	if name.Filepath() != want.Filepath() {
+		return nil, fmt.Errorf("%s: no such apple foundation model", name)
+	}
+
+	config := ConfigV2{
+		OS:            "linux",
+		Architecture:  "amd64",
+		RootFS:        RootFS{Type: "layers"},
+		ModelFormat:   "apple",
+		ModelFamily:   "apple",
+		ModelType:     "apple",
+		FileType:      "apple",
+		ModelFamilies: []string{"apple"},
+	}
+
+	configData, err := json.Marshal(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal config: %w", err)
+	}
+
+	configLayer, err := p.cacheLayer(configData, "application/vnd.ollama.image.config")
+	if err != nil {
+		return nil, fmt.Errorf("failed to cache config layer: %w", err)
+	}
+
+	templateLayer, err := p.cacheLayer(appleFoundationModelTemplate, "application/vnd.ollama.image.template")
+	if err != nil {
+		return nil, fmt.Errorf("failed to cache template layer: %w", err)
+	}
+
+	tokenizerLayer, err := p.cacheLayer(appleFoundationModelTokenizer, "application/vnd.ollama.image.tokenizer")
+	if err != nil {
+		return nil, fmt.Errorf("failed to cache tokenizer layer: %w", err)
+	}
+
+	modelLayer, err := p.cacheLayer(appleFoundationModelStub, p.MediaType())
+	if err != nil {
+		return nil, fmt.Errorf("failed to cache model layer: %w", err)
+	}
+
+	manifest := &Manifest{
+		SchemaVersion:          2,
+		MediaType:              MediaTypeDockerManifest,
+		Config:                 configLayer,
+		Layers:                 []Layer{modelLayer, templateLayer, tokenizerLayer},
+		IsAppleFoundationModel: true,
+	}
+
+	manifests, err := GetManifestPath()
+	if err != nil {
+		return nil, err
+	}
+
+	cache := filepath.Join(manifests, ".providers", "apple", name.Filepath())
+	if err := os.MkdirAll(filepath.Dir(cache), 0o755); err != nil {
+		return nil, err
+	}
+
+	f, err := os.Create(cache)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	if err := json.NewEncoder(f).Encode(manifest); err != nil {
+		return nil, err
+	}
+
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	manifest.filepath = cache
+	manifest.fi = fi
+
+	return manifest, nil
+}
+
+// cacheLayer writes data to the blob store, keyed by its sha256 digest, and
+// returns the corresponding Layer. Writes are idempotent: a blob that
+// already exists on disk is left untouched.
+func (p *appleFoundationModelProvider) cacheLayer(data []byte, mediaType string) (Layer, error) {
+	sum := sha256.Sum256(data)
+	digest := fmt.Sprintf("sha256:%x", sum)
+
+	blob, err := GetBlobsPath(digest)
+	if err != nil {
+		return Layer{}, err
+	}
+
+	if _, err := os.Stat(blob); errors.Is(err, os.ErrNotExist) {
+		if err := os.MkdirAll(filepath.Dir(blob), 0o755); err != nil {
+			return Layer{}, err
+		}
+
+		if err := os.WriteFile(blob, data, 0o644); err != nil {
+			return Layer{}, err
+		}
+	} else if err != nil {
+		return Layer{}, err
+	}
+
+	return Layer{
+		Digest:    digest,
+		MediaType: mediaType,
+		Size:      int64(len(data)),
+	}, nil
+}
+
+var appleFoundationModelTemplate = []byte(`{{ if .System }}<|im_start|>system
+{{ .System }}<|im_end|>
+{{ end }}{{ if .Prompt }}<|im_start|>user
+{{ .Prompt }}<|im_end|>
+<|im_start|>assistant
+{{ end }}{{ .Response }}<|im_end|>`)
+
+// appleFoundationModelStub stands in for the actual model weights, which
+// are not a file on disk: the Foundation Models framework is addressed by
+// name at generation time, not loaded from a blob.
+var appleFoundationModelStub = []byte("application/vnd.ollama.image.applefoundationmodel")
+
+// appleFoundationModelTokenizer is a minimal tokenizer.json-style vocab
+// covering the special tokens used by appleFoundationModelTemplate, so the
+// runner can tokenize template boilerplate without colliding with real
+// vocab IDs. A real Apple Foundation Models integration would ship its own
+// tokenizer layer in its place.
+var appleFoundationModelTokenizer = []byte(`{
+	"model": {
+		"vocab": {
+			"<|im_start|>": 1,
+			"<|im_end|>": 2,
+			"system": 3,
+			"user": 4,
+			"assistant": 5
+		}
+	}
+}`)
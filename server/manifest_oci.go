@@ -0,0 +1,77 @@
+package server
+
+import "fmt"
+
+// Media types recognized by ParseNamedManifest; WriteManifest and
+// WriteOCIManifest write the two single-manifest types. Alongside
+// the legacy Docker distribution schema, Ollama understands OCI image-spec
+// v1 manifests and image indexes, so models published to any
+// OCI-conformant registry (Harbor, GHCR, Zot, ...) can be pulled without
+// conversion.
+const (
+	MediaTypeDockerManifest     = "application/vnd.docker.distribution.manifest.v2+json"
+	MediaTypeDockerManifestList = "application/vnd.docker.distribution.manifest.list.v2+json"
+	MediaTypeOCIManifest        = "application/vnd.oci.image.manifest.v1+json"
+	MediaTypeOCIImageIndex      = "application/vnd.oci.image.index.v1+json"
+)
+
+// IsOCI reports whether m uses the OCI image-spec v1 manifest media type
+// rather than the legacy Docker distribution schema.
+func (m *Manifest) IsOCI() bool {
+	return m.MediaType == MediaTypeOCIManifest
+}
+
+// Platform identifies the OS/architecture a manifest in an image index
+// targets, mirroring the platform object in the OCI image-spec.
+type Platform struct {
+	OS           string `json:"os"`
+	Architecture string `json:"architecture"`
+	Variant      string `json:"variant,omitempty"`
+}
+
+// ManifestDescriptor references a manifest belonging to an image index,
+// along with the platform it targets.
+type ManifestDescriptor struct {
+	MediaType string   `json:"mediaType"`
+	Digest    string   `json:"digest"`
+	Size      int64    `json:"size"`
+	Platform  Platform `json:"platform"`
+}
+
+// ManifestList is an OCI image index or Docker manifest list: a pointer to
+// one manifest per platform, addressed by digest in the blob store.
+type ManifestList struct {
+	SchemaVersion int                  `json:"schemaVersion"`
+	MediaType     string               `json:"mediaType"`
+	Manifests     []ManifestDescriptor `json:"manifests"`
+}
+
+// Select returns the descriptor in the index matching os and architecture.
+// If variant is non-empty it must match exactly; otherwise Select prefers a
+// descriptor with no variant but falls back to the first platform match.
+func (l ManifestList) Select(os, architecture, variant string) (ManifestDescriptor, error) {
+	var fallback *ManifestDescriptor
+	for i, d := range l.Manifests {
+		if d.Platform.OS != os || d.Platform.Architecture != architecture {
+			continue
+		}
+
+		if d.Platform.Variant == variant {
+			return l.Manifests[i], nil
+		}
+
+		if variant == "" && fallback == nil {
+			fallback = &l.Manifests[i]
+		}
+	}
+
+	if fallback != nil {
+		return *fallback, nil
+	}
+
+	return ManifestDescriptor{}, fmt.Errorf("no manifest for platform %s/%s", os, architecture)
+}
+
+func isManifestList(mediaType string) bool {
+	return mediaType == MediaTypeOCIImageIndex || mediaType == MediaTypeDockerManifestList
+}
@@ -0,0 +1,34 @@
+package server
+
+import (
+	"github.com/ollama/ollama/types/model"
+)
+
+// ManifestProvider produces manifests for models that are not stored as
+// plain files under the manifest tree, e.g. models backed by an on-device
+// inference framework rather than a registry pull. Providers are merged
+// into the results of Manifests alongside the on-disk manifest tree.
+type ManifestProvider interface {
+	// List returns the names of all models this provider can produce a
+	// manifest for. It may return an empty slice if the provider is
+	// currently disabled (missing hardware, feature flag off, etc.).
+	List() ([]model.Name, error)
+
+	// Get returns the manifest for name. It returns an error if name is
+	// not recognized by this provider.
+	Get(name model.Name) (*Manifest, error)
+
+	// MediaType returns the media type used for this provider's model
+	// layer, e.g. "application/vnd.ollama.image.applefoundationmodel".
+	MediaType() string
+}
+
+var manifestProviders []ManifestProvider
+
+// RegisterManifestProvider registers a ManifestProvider whose models are
+// merged into the results of Manifests. Providers are consulted in the
+// order they were registered; a later provider overrides an earlier one
+// for any name they both produce.
+func RegisterManifestProvider(p ManifestProvider) {
+	manifestProviders = append(manifestProviders, p)
+}
@@ -0,0 +1,166 @@
+package server
+
+import (
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/ollama/ollama/types/model"
+)
+
+// TrustPolicy lists the ed25519 public keys trusted to sign models, per
+// registry. It mirrors the shape of containers-image's policy.json: a
+// registry with no specific entry falls back to Default.
+type TrustPolicy struct {
+	Default    []ed25519.PublicKey            `json:"default,omitempty"`
+	Registries map[string][]ed25519.PublicKey `json:"registries,omitempty"`
+}
+
+func (p *TrustPolicy) keysFor(registry string) []ed25519.PublicKey {
+	if p == nil {
+		return nil
+	}
+	if keys, ok := p.Registries[registry]; ok {
+		return validPublicKeys(keys)
+	}
+	return validPublicKeys(p.Default)
+}
+
+// validPublicKeys filters keys down to those ed25519.Verify can actually
+// check, dropping anything else. ed25519.Verify panics, rather than
+// returning false, for a key whose length isn't ed25519.PublicKeySize, and
+// these keys come straight from operator-supplied trust-policy JSON.
+func validPublicKeys(keys []ed25519.PublicKey) []ed25519.PublicKey {
+	var valid []ed25519.PublicKey
+	for _, key := range keys {
+		if len(key) != ed25519.PublicKeySize {
+			slog.Warn("ignoring malformed trust policy key", "length", len(key))
+			continue
+		}
+		valid = append(valid, key)
+	}
+	return valid
+}
+
+// LoadTrustPolicy reads a TrustPolicy from a JSON file.
+func LoadTrustPolicy(path string) (*TrustPolicy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var p TrustPolicy
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, err
+	}
+
+	return &p, nil
+}
+
+// registryOf returns the registry host a model name was pulled from, e.g.
+// "registry.ollama.ai" for "registry.ollama.ai/library/llama3:latest".
+func registryOf(name model.Name) string {
+	s := name.String()
+	if i := strings.Index(s, "/"); i >= 0 {
+		return s[:i]
+	}
+	return s
+}
+
+// signaturePath returns the path of the detached signature sidecar for the
+// manifest stored at p.
+func signaturePath(p string) string {
+	return p + ".sig"
+}
+
+// signManifest signs data (a manifest's canonical JSON encoding) with key
+// and writes the detached signature to p's ".sig" sidecar.
+func signManifest(p string, data []byte, key ed25519.PrivateKey) error {
+	return os.WriteFile(signaturePath(p), ed25519.Sign(key, data), 0o644)
+}
+
+// verifyManifestSignature checks the ".sig" sidecar for the manifest at p
+// against the keys policy trusts for registry, failing closed: a missing
+// signature, or one that doesn't match any trusted key, is an error.
+func verifyManifestSignature(p string, data []byte, registry string, policy *TrustPolicy) error {
+	sig, err := os.ReadFile(signaturePath(p))
+	if errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("%s: signature verification is required but no signature is present", p)
+	} else if err != nil {
+		return err
+	}
+
+	for _, key := range policy.keysFor(registry) {
+		if ed25519.Verify(key, data, sig) {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%s: signature does not match any key trusted for %s", p, registry)
+}
+
+// signingKey loads the ed25519 private key WriteManifest signs with, from
+// the PEM-encoded PKCS#8 file referenced by OLLAMA_SIGNING_KEY. Signing is
+// skipped (not an error) when the variable is unset.
+func signingKey() (ed25519.PrivateKey, bool) {
+	path := os.Getenv("OLLAMA_SIGNING_KEY")
+	if path == "" {
+		return nil, false
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		slog.Warn("failed to read manifest signing key", "path", path, "error", err)
+		return nil, false
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		slog.Warn("failed to decode manifest signing key: no PEM block found", "path", path)
+		return nil, false
+	}
+
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		slog.Warn("failed to parse manifest signing key", "path", path, "error", err)
+		return nil, false
+	}
+
+	key, ok := parsed.(ed25519.PrivateKey)
+	if !ok {
+		slog.Warn("manifest signing key is not ed25519", "path", path)
+		return nil, false
+	}
+
+	return key, true
+}
+
+// verificationRequired reports whether ParseNamedManifest must verify a
+// manifest's signature before returning it.
+func verificationRequired() bool {
+	return os.Getenv("OLLAMA_VERIFY_SIGNATURES") == "1"
+}
+
+// trustPolicy loads the TrustPolicy referenced by OLLAMA_TRUST_POLICY. It
+// fails closed: if the variable is unset or the file can't be read, no
+// keys are trusted and verification will reject every manifest.
+func trustPolicy() *TrustPolicy {
+	path := os.Getenv("OLLAMA_TRUST_POLICY")
+	if path == "" {
+		return &TrustPolicy{}
+	}
+
+	policy, err := LoadTrustPolicy(path)
+	if err != nil {
+		slog.Warn("failed to load trust policy", "path", path, "error", err)
+		return &TrustPolicy{}
+	}
+
+	return policy
+}